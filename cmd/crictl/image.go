@@ -23,12 +23,17 @@ import (
 	"sort"
 	"strings"
 	"text/tabwriter"
+	"time"
 
 	units "github.com/docker/go-units"
 	"github.com/sirupsen/logrus"
 	"github.com/urfave/cli"
 	"golang.org/x/net/context"
 	pb "k8s.io/kubernetes/pkg/kubelet/apis/cri/runtime/v1alpha2"
+
+	"github.com/kubernetes-sigs/cri-tools/pkg/auth"
+	"github.com/kubernetes-sigs/cri-tools/pkg/filters"
+	"github.com/kubernetes-sigs/cri-tools/pkg/pullpolicy"
 )
 
 type imageByRef []*pb.Image
@@ -56,6 +61,21 @@ var pullImageCommand = cli.Command{
 			Value: "",
 			Usage: "Use `USERNAME[:PASSWORD]` for accessing the registry",
 		},
+		cli.StringFlag{
+			Name:  "authfile",
+			Value: "",
+			Usage: "Path to the authentication file. Defaults to $REGISTRY_AUTH_FILE, $DOCKER_CONFIG/config.json, $HOME/.docker/config.json, $XDG_RUNTIME_DIR/containers/auth.json in that order",
+		},
+		cli.StringFlag{
+			Name:  "creds-helper",
+			Value: "",
+			Usage: "Use the `NAME` credential helper (docker-credential-NAME) for accessing the registry, as configured via credHelpers in the auth file",
+		},
+		cli.StringFlag{
+			Name:  "pull-policy",
+			Value: string(pullpolicy.Always),
+			Usage: "Pull policy, one of: always|missing|never|newer",
+		},
 	},
 	ArgsUsage: "NAME[:TAG|@DIGEST]",
 	Action: func(context *cli.Context) error {
@@ -68,16 +88,17 @@ var pullImageCommand = cli.Command{
 			return err
 		}
 
-		var auth *pb.AuthConfig
-		if context.IsSet("creds") {
-			var err error
-			auth, err = getAuth(context.String("creds"))
-			if err != nil {
-				return err
-			}
+		authConfig, err := resolvePullAuth(context, imageName)
+		if err != nil {
+			return err
+		}
+
+		policy, err := pullpolicy.Parse(context.String("pull-policy"))
+		if err != nil {
+			return err
 		}
 
-		r, err := PullImage(imageClient, imageName, auth)
+		r, err := PullImage(imageClient, imageName, authConfig, policy)
 		if err != nil {
 			return fmt.Errorf("pulling image failed: %v", err)
 		}
@@ -113,6 +134,10 @@ var listImageCommand = cli.Command{
 			Name:  "no-trunc",
 			Usage: "Show output without truncating the ID",
 		},
+		cli.StringSliceFlag{
+			Name:  "filter, f",
+			Usage: "Filter output based on conditions provided: reference=<glob>, dangling=true|false, before=<image>, since=<image>, id=<prefix>, label=<key>[=<value>]",
+		},
 	},
 	Action: func(context *cli.Context) error {
 		if err := getImageClient(context); err != nil {
@@ -123,6 +148,13 @@ var listImageCommand = cli.Command{
 		if err != nil {
 			return fmt.Errorf("listing images failed: %v", err)
 		}
+
+		if raw := context.StringSlice("filter"); len(raw) > 0 {
+			r.Images, err = filterImages(r.Images, raw)
+			if err != nil {
+				return fmt.Errorf("applying image filter failed: %v", err)
+			}
+		}
 		sort.Sort(imageByRef(r.Images))
 
 		switch context.String("output") {
@@ -301,6 +333,146 @@ var removeImageCommand = cli.Command{
 	},
 }
 
+var pruneImageCommand = cli.Command{
+	Name:  "prune",
+	Usage: "Remove all unused images",
+	Flags: []cli.Flag{
+		cli.BoolFlag{
+			Name:  "all, a",
+			Usage: "Remove all images not used by any container, not just dangling ones",
+		},
+		cli.StringSliceFlag{
+			Name:  "filter",
+			Usage: "Filter prune candidates: label=<key>[=<value>], until=<duration>, reference=<glob>",
+		},
+		cli.BoolFlag{
+			Name:  "force, f",
+			Usage: "Do not prompt for confirmation",
+		},
+		cli.BoolFlag{
+			Name:  "dry-run",
+			Usage: "Do not remove images, just show what would be removed",
+		},
+	},
+	Action: func(context *cli.Context) error {
+		if err := getImageClient(context); err != nil {
+			return err
+		}
+
+		f, err := filters.Parse(context.StringSlice("filter"))
+		if err != nil {
+			return fmt.Errorf("invalid filter: %v", err)
+		}
+
+		r, err := ListImages(imageClient, "")
+		if err != nil {
+			return fmt.Errorf("listing images failed: %v", err)
+		}
+
+		all := context.Bool("all")
+		var inUse map[string]bool
+		if all {
+			if err := getRuntimeClient(context); err != nil {
+				return err
+			}
+			containers, err := ListContainers(runtimeClient, nil)
+			if err != nil {
+				return fmt.Errorf("listing containers failed: %v", err)
+			}
+			inUse = map[string]bool{}
+			for _, c := range containers.Containers {
+				inUse[c.ImageRef] = true
+			}
+		}
+
+		needInfo := len(f.Labels) > 0 || f.Until != ""
+		var candidates []*pb.Image
+		var reclaimed uint64
+		for _, image := range r.Images {
+			if all {
+				if inUse[image.Id] {
+					continue
+				}
+			} else if !filters.IsDangling(image) {
+				continue
+			}
+
+			var info map[string]string
+			if needInfo {
+				status, err := ImageStatus(imageClient, image.Id, true)
+				if err != nil {
+					return fmt.Errorf("image status for %q failed: %v", image.Id, err)
+				}
+				info = filters.ParseLabels(status.Info)
+				if info == nil {
+					info = map[string]string{}
+				}
+				if f.Until != "" {
+					d, err := time.ParseDuration(f.Until)
+					if err != nil {
+						return fmt.Errorf("invalid until filter %q: %v", f.Until, err)
+					}
+					created, ok := filters.CreatedAt(status.Info)
+					if ok && created.After(time.Now().Add(-d)) {
+						continue
+					}
+				}
+			}
+			if !f.Match(image, info) {
+				continue
+			}
+
+			candidates = append(candidates, image)
+			reclaimed += image.GetSize_()
+		}
+
+		if len(candidates) == 0 {
+			fmt.Println("No images to prune")
+			return nil
+		}
+
+		if !context.Bool("force") && !context.Bool("dry-run") {
+			fmt.Println("The following images will be removed:")
+			for _, image := range candidates {
+				fmt.Printf("  %s\n", image.Id)
+			}
+			fmt.Print("Are you sure you want to continue? [y/N] ")
+			var answer string
+			fmt.Scanln(&answer)
+			if strings.ToLower(strings.TrimSpace(answer)) != "y" {
+				return nil
+			}
+		}
+
+		for _, image := range candidates {
+			if context.Bool("dry-run") {
+				fmt.Printf("Would remove: %s\n", image.Id)
+				continue
+			}
+			if _, err := RemoveImage(imageClient, image.Id); err != nil {
+				return fmt.Errorf("error of removing image %q: %v", image.Id, err)
+			}
+			fmt.Printf("Deleted: %s\n", image.Id)
+		}
+
+		fmt.Printf("Total reclaimed space: %s\n", units.HumanSize(float64(reclaimed)))
+		return nil
+	},
+}
+
+// resolvePullAuth picks the registry credentials for imageName: an explicit
+// --creds wins, then an explicit --creds-helper, then the auth-file lookup
+// (--authfile, or its well-known fallbacks) that docker/podman also honor.
+func resolvePullAuth(context *cli.Context, imageName string) (*pb.AuthConfig, error) {
+	if context.IsSet("creds") {
+		return getAuth(context.String("creds"))
+	}
+	if helper := context.String("creds-helper"); helper != "" {
+		return auth.ResolveAuthWithHelper(imageName, helper)
+	}
+	return auth.ResolveAuth(imageName, context.String("authfile"))
+}
+
 func parseCreds(creds string) (string, string, error) {
 	if creds == "" {
 		return "", "", errors.New("credentials can't be empty")
@@ -355,16 +527,137 @@ func normalizeRepoDigest(repoDigests []string) (string, string) {
 	return repoDigestPair[0], repoDigestPair[1]
 }
 
+// filterImages applies the `--filter`/`-f` predicates parsed from raw to
+// images, resolving before=/since=/label= against the runtime as needed.
+func filterImages(images []*pb.Image, raw []string) ([]*pb.Image, error) {
+	f, err := filters.Parse(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	var before, since temporalRef
+	if f.BeforeImage != "" {
+		before, err = resolveCreatedAt(f.BeforeImage, images)
+		if err != nil {
+			return nil, fmt.Errorf("resolving before=%q: %v", f.BeforeImage, err)
+		}
+	}
+	if f.SinceImage != "" {
+		since, err = resolveCreatedAt(f.SinceImage, images)
+		if err != nil {
+			return nil, fmt.Errorf("resolving since=%q: %v", f.SinceImage, err)
+		}
+	}
+
+	needInfo := len(f.Labels) > 0 || f.BeforeImage != "" || f.SinceImage != ""
+
+	var out []*pb.Image
+	for i, image := range images {
+		var info, rawInfo map[string]string
+		if needInfo {
+			status, err := ImageStatus(imageClient, image.Id, true)
+			if err != nil {
+				return nil, fmt.Errorf("image status for %q failed: %v", image.Id, err)
+			}
+			rawInfo = status.Info
+			info = filters.ParseLabels(status.Info)
+			if info == nil {
+				info = map[string]string{}
+			}
+		}
+		if f.BeforeImage != "" && !before.isBefore(i, rawInfo) {
+			continue
+		}
+		if f.SinceImage != "" && !since.isAfter(i, rawInfo) {
+			continue
+		}
+		if f.Match(image, info) {
+			out = append(out, image)
+		}
+	}
+	return out, nil
+}
+
+// temporalRef is a resolved before=/since= reference: either a creation time
+// recovered from the runtime's verbose Info, or, when the runtime reports
+// none, the reference image's position in images (the runtime's ListImages
+// order), used as a recency proxy in which earlier entries are treated as
+// newer, the way libimage falls back to list order.
+type temporalRef struct {
+	createdAt time.Time
+	hasTime   bool
+	index     int
+}
+
+// isBefore reports whether the image at position i in the same list, with
+// its verbose ImageStatus info, was created before r: by timestamp when both are known,
+// otherwise by list position relative to r.index.
+func (r temporalRef) isBefore(i int, info map[string]string) bool {
+	if created, ok := filters.CreatedAt(info); ok && r.hasTime {
+		return created.Before(r.createdAt)
+	}
+	if r.index < 0 {
+		return true
+	}
+	return i > r.index
+}
+
+// isAfter is isBefore's since= counterpart.
+func (r temporalRef) isAfter(i int, info map[string]string) bool {
+	if created, ok := filters.CreatedAt(info); ok && r.hasTime {
+		return created.After(r.createdAt)
+	}
+	if r.index < 0 {
+		return true
+	}
+	return i < r.index
+}
+
+// resolveCreatedAt looks up ref via ImageStatus and returns it as a
+// temporalRef: its creation time when the runtime reports one, and always
+// its position within images (the full, unfiltered list), so filterImages
+// can fall back to list order for candidates the runtime can't timestamp.
+func resolveCreatedAt(ref string, images []*pb.Image) (temporalRef, error) {
+	status, err := ImageStatus(imageClient, ref, true)
+	if err != nil {
+		return temporalRef{}, err
+	}
+	if status.Image == nil {
+		return temporalRef{}, fmt.Errorf("no such image %q", ref)
+	}
+
+	index := -1
+	for i, image := range images {
+		if image.Id == status.Image.Id {
+			index = i
+			break
+		}
+	}
+
+	created, ok := filters.CreatedAt(status.Info)
+	return temporalRef{createdAt: created, hasTime: ok, index: index}, nil
+}
+
 // PullImage sends a PullImageRequest to the server, and parses
-// the returned PullImageResponse.
-func PullImage(client pb.ImageServiceClient, image string, auth *pb.AuthConfig) (resp *pb.PullImageResponse, err error) {
+// the returned PullImageResponse. When policy permits skipping the pull
+// (missing/never/newer and a satisfying local image already exists), it
+// synthesizes a PullImageResponse from that local image instead.
+func PullImage(client pb.ImageServiceClient, image string, authConfig *pb.AuthConfig, policy pullpolicy.Policy) (resp *pb.PullImageResponse, err error) {
+	shouldPull, local, err := pullpolicy.ShouldPull(client, image, authConfig, policy)
+	if err != nil {
+		return nil, err
+	}
+	if !shouldPull {
+		return &pb.PullImageResponse{ImageRef: local.Id}, nil
+	}
+
 	request := &pb.PullImageRequest{
 		Image: &pb.ImageSpec{
 			Image: image,
 		},
 	}
-	if auth != nil {
-		request.Auth = auth
+	if authConfig != nil {
+		request.Auth = authConfig
 	}
 	logrus.Debugf("PullImageRequest: %v", request)
 	resp, err = client.PullImage(context.Background(), request)
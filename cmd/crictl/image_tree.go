@@ -0,0 +1,258 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	units "github.com/docker/go-units"
+	"github.com/urfave/cli"
+	pb "k8s.io/kubernetes/pkg/kubelet/apis/cri/runtime/v1alpha2"
+)
+
+// ociRootFSAndHistory is the rootfs/history subset of an OCI image config,
+// as embedded (at different nesting depths, hence the dedicated unmarshal
+// targets below) in the verbose ImageStatus "info" payload.
+type ociRootFSAndHistory struct {
+	RootFS struct {
+		DiffIDs []string `json:"diff_ids"`
+	} `json:"rootfs"`
+	History []struct {
+		CreatedBy  string `json:"created_by"`
+		EmptyLayer bool   `json:"empty_layer"`
+	} `json:"history"`
+}
+
+// containerdImageInfo models containerd's info["info"] shape, which nests
+// the OCI image config a level deeper than its top-level keys; this mirrors
+// pkg/filters/info.go's containerdImageInfo, which needs the same nesting
+// to reach Config.Labels.
+type containerdImageInfo struct {
+	ImageSpec ociRootFSAndHistory `json:"imageSpec"`
+}
+
+// imageInfoLayers is the subset of the verbose ImageStatus "info" payload
+// crictl needs to render a layer tree, normalized to a single flat shape
+// regardless of which runtime-specific envelope it came from.
+type imageInfoLayers = ociRootFSAndHistory
+
+// treeLayer is one rendered row of `crictl image tree`. CRI has no RPC that
+// reports per-layer size (that lives in each runtime's internal snapshot
+// store), so every layer renders as "<missing>" until one is added.
+type treeLayer struct {
+	DiffID     string   `json:"diffID"`
+	CreatedBy  string   `json:"createdBy,omitempty"`
+	SharedWith []string `json:"sharedWith,omitempty"`
+}
+
+// imageTree is the JSON shape emitted by `--output json`.
+type imageTree struct {
+	Reference string      `json:"reference"`
+	ID        string      `json:"id"`
+	Size      int64       `json:"size"`
+	Layers    []treeLayer `json:"layers"`
+}
+
+var imageTreeCommand = cli.Command{
+	Name:      "tree",
+	Usage:     "Show the layer/parent tree of an image",
+	ArgsUsage: "IMAGEID",
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "output, o",
+			Usage: "Output format, one of: json|table (default: table)",
+		},
+		cli.BoolFlag{
+			Name:  "whatrequires",
+			Usage: "List images that require (share a layer with) the given image, per layer",
+		},
+	},
+	Action: func(context *cli.Context) error {
+		id := context.Args().First()
+		if id == "" {
+			return cli.ShowSubcommandHelp(context)
+		}
+		if err := getImageClient(context); err != nil {
+			return err
+		}
+
+		status, err := ImageStatus(imageClient, id, true)
+		if err != nil {
+			return fmt.Errorf("image status for %q failed: %v", id, err)
+		}
+		if status.Image == nil {
+			return fmt.Errorf("no such image %q", id)
+		}
+
+		sharedWith, err := computeSharedLayers(status.Image.Id)
+		if err != nil {
+			return fmt.Errorf("computing shared layers failed: %v", err)
+		}
+
+		info, err := parseImageLayers(status.Info)
+		if err != nil {
+			return fmt.Errorf("parsing layer info for %q failed: %v", id, err)
+		}
+
+		tree := imageTree{
+			Reference: primaryReference(status.Image),
+			ID:        status.Image.Id,
+			Size:      int64(status.Image.GetSize_()),
+		}
+		for _, diffID := range info.RootFS.DiffIDs {
+			tree.Layers = append(tree.Layers, treeLayer{
+				DiffID:     diffID,
+				CreatedBy:  createdByFor(info, len(tree.Layers)),
+				SharedWith: sharedWith[diffID],
+			})
+		}
+
+		if context.String("output") == "json" {
+			out, err := json.MarshalIndent(tree, "", "  ")
+			if err != nil {
+				return fmt.Errorf("marshaling tree to json failed: %v", err)
+			}
+			fmt.Println(string(out))
+			return nil
+		}
+
+		renderTree(tree, context.Bool("whatrequires"))
+		return nil
+	},
+}
+
+// primaryReference returns the first RepoTag, falling back to the first
+// RepoDigest and finally the image ID, mirroring how `crictl images` labels
+// an otherwise tagless image.
+func primaryReference(image *pb.Image) string {
+	if len(image.RepoTags) > 0 {
+		return image.RepoTags[0]
+	}
+	if len(image.RepoDigests) > 0 {
+		return image.RepoDigests[0]
+	}
+	return image.Id
+}
+
+// parseImageLayers reads rootfs/history information from containerd's
+// info["info"].imageSpec shape only; no CRI-O shape is parsed, so CRI-O
+// images yield an empty (zero-layer) result. It returns that empty result,
+// rather than an error, whenever the containerd shape yields nothing, since a
+// runtime that doesn't expose this is not itself a failure.
+func parseImageLayers(info map[string]string) (*imageInfoLayers, error) {
+	raw, ok := info["info"]
+	if !ok {
+		return &imageInfoLayers{}, nil
+	}
+
+	var containerd containerdImageInfo
+	if err := json.Unmarshal([]byte(raw), &containerd); err == nil && len(containerd.ImageSpec.RootFS.DiffIDs) > 0 {
+		return &containerd.ImageSpec, nil
+	}
+
+	// CRI-O does not currently expose a documented rootfs/history shape in
+	// its verbose Info map, so there is nothing further to try here.
+	return &imageInfoLayers{}, nil
+}
+
+func createdByFor(info *imageInfoLayers, layerIndex int) string {
+	// History includes empty (metadata-only) entries that don't correspond
+	// to a rootfs layer, so walk it counting only non-empty ones.
+	seen := -1
+	for _, h := range info.History {
+		if h.EmptyLayer {
+			continue
+		}
+		seen++
+		if seen == layerIndex {
+			return truncateCreatedBy(h.CreatedBy)
+		}
+	}
+	return ""
+}
+
+func truncateCreatedBy(createdBy string) string {
+	const maxLen = 60
+	createdBy = strings.TrimSpace(createdBy)
+	if len(createdBy) > maxLen {
+		return createdBy[:maxLen] + "..."
+	}
+	return createdBy
+}
+
+// computeSharedLayers fetches verbose ImageStatus for every local image and
+// indexes diff_ids to the set of image IDs (other than self) they appear in.
+func computeSharedLayers(self string) (map[string][]string, error) {
+	list, err := ListImages(imageClient, "")
+	if err != nil {
+		return nil, fmt.Errorf("listing images failed: %v", err)
+	}
+
+	shared := map[string][]string{}
+	for _, image := range list.Images {
+		status, err := ImageStatus(imageClient, image.Id, true)
+		if err != nil {
+			return nil, fmt.Errorf("image status for %q failed: %v", image.Id, err)
+		}
+		info, err := parseImageLayers(status.Info)
+		if err != nil {
+			continue
+		}
+		for _, diffID := range info.RootFS.DiffIDs {
+			if image.Id != self {
+				shared[diffID] = append(shared[diffID], image.Id)
+			}
+		}
+	}
+	return shared, nil
+}
+
+func renderTree(tree imageTree, whatrequires bool) {
+	size := units.HumanSizeWithPrecision(float64(tree.Size), 3)
+	fmt.Printf("%s (%s) Size: %s\n", tree.Reference, truncatedID(tree.ID), size)
+
+	for i, layer := range tree.Layers {
+		prefix := "├─"
+		if i == len(tree.Layers)-1 {
+			prefix = "└─"
+		}
+		// CRI exposes no per-layer size RPC; every layer is "<missing>" today.
+		line := fmt.Sprintf("%s ID: %s Size: <missing>", prefix, truncatedID(layer.DiffID))
+		if layer.CreatedBy != "" {
+			line += fmt.Sprintf(" Created by: %s", layer.CreatedBy)
+		}
+		if len(layer.SharedWith) > 0 {
+			line += fmt.Sprintf(" * shared with %d images", len(layer.SharedWith))
+		}
+		fmt.Println(line)
+		if whatrequires {
+			for _, other := range layer.SharedWith {
+				fmt.Printf("   └─ %s\n", truncatedID(other))
+			}
+		}
+	}
+}
+
+func truncatedID(id string) string {
+	id = strings.TrimPrefix(id, "sha256:")
+	if len(id) > truncatedIDLen {
+		id = id[:truncatedIDLen]
+	}
+	return id
+}
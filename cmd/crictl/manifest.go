@@ -0,0 +1,138 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/urfave/cli"
+
+	"github.com/kubernetes-sigs/cri-tools/internal/registry"
+	"github.com/kubernetes-sigs/cri-tools/pkg/auth"
+)
+
+var manifestCommand = cli.Command{
+	Name:        "manifest",
+	Usage:       "Manage multi-arch image manifests",
+	Subcommands: []cli.Command{manifestInspectCommand},
+}
+
+var manifestInspectCommand = cli.Command{
+	Name:      "inspect",
+	Usage:     "Display the manifest, or manifest list, of an image",
+	ArgsUsage: "NAME[:TAG|@DIGEST]",
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "authfile",
+			Value: "",
+			Usage: "Path to the authentication file. Defaults to $REGISTRY_AUTH_FILE, $DOCKER_CONFIG/config.json, $HOME/.docker/config.json, $XDG_RUNTIME_DIR/containers/auth.json in that order",
+		},
+		cli.BoolFlag{
+			Name:  "verify",
+			Usage: "Re-hash the manifest body and compare it against the registry's Docker-Content-Digest header",
+		},
+	},
+	Action: func(context *cli.Context) error {
+		image := context.Args().First()
+		if image == "" {
+			return cli.ShowSubcommandHelp(context)
+		}
+
+		authConfig, err := auth.ResolveAuth(image, context.String("authfile"))
+		if err != nil {
+			return err
+		}
+
+		manifest, err := registry.GetManifest(image, authConfig)
+		if err != nil {
+			return fmt.Errorf("inspecting manifest for %q failed: %v", image, err)
+		}
+
+		if context.Bool("verify") {
+			if err := verifyManifestDigest(manifest); err != nil {
+				return err
+			}
+		}
+
+		var pretty bytes.Buffer
+		if err := json.Indent(&pretty, manifest.Body, "", "  "); err != nil {
+			return fmt.Errorf("parsing manifest json for %q failed: %v", image, err)
+		}
+		fmt.Println(pretty.String())
+
+		if manifest.IsList() {
+			if err := printManifestList(manifest.Body); err != nil {
+				return fmt.Errorf("summarizing manifest list for %q failed: %v", image, err)
+			}
+		}
+		return nil
+	},
+}
+
+func verifyManifestDigest(manifest *registry.Manifest) error {
+	if manifest.Digest == "" {
+		return fmt.Errorf("registry did not return a Docker-Content-Digest header to verify against")
+	}
+	sum := sha256.Sum256(manifest.Body)
+	computed := "sha256:" + hex.EncodeToString(sum[:])
+	if computed != manifest.Digest {
+		return fmt.Errorf("digest mismatch: registry reported %s, computed %s", manifest.Digest, computed)
+	}
+	return nil
+}
+
+// manifestDescriptor is one entry of a manifest list / image index.
+type manifestDescriptor struct {
+	Digest    string `json:"digest"`
+	Size      int64  `json:"size"`
+	MediaType string `json:"mediaType"`
+	Platform  struct {
+		OS           string `json:"os"`
+		Architecture string `json:"architecture"`
+		Variant      string `json:"variant"`
+	} `json:"platform"`
+}
+
+type manifestIndex struct {
+	Manifests []manifestDescriptor `json:"manifests"`
+}
+
+// printManifestList renders a DIGEST/PLATFORM/SIZE/MEDIATYPE table
+// summarizing each child manifest of a manifest list / image index.
+func printManifestList(body []byte) error {
+	var index manifestIndex
+	if err := json.Unmarshal(body, &index); err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 20, 1, 3, ' ', 0)
+	fmt.Fprintln(w, "DIGEST\tPLATFORM\tSIZE\tMEDIATYPE")
+	for _, m := range index.Manifests {
+		platform := m.Platform.OS + "/" + m.Platform.Architecture
+		if m.Platform.Variant != "" {
+			platform += "/" + m.Platform.Variant
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\n", m.Digest, platform, m.Size, m.MediaType)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,284 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package registry is a minimal OCI/Docker distribution registry client for
+// the crictl subcommands (manifest inspect, the digest/config/blob commands
+// it paves the way for, and the pull-policy=newer digest check) that need to
+// talk to a registry directly because the CRI ImageService has no
+// corresponding RPC. It implements the registry v2 Bearer challenge/token
+// exchange (RFC for "Docker Registry HTTP API V2, Token Authentication"),
+// which Docker Hub, GCR, GHCR, ECR and quay.io all require even for
+// anonymous/public pulls.
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	pb "k8s.io/kubernetes/pkg/kubelet/apis/cri/runtime/v1alpha2"
+)
+
+// manifestAccept is the set of media types crictl asks a registry for when
+// inspecting a manifest, in preference order: index/list first, falling
+// back to single-platform manifests for registries or images too old to
+// have a list.
+var manifestAccept = strings.Join([]string{
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+}, ", ")
+
+// Manifest is the result of fetching an image's manifest (or manifest list)
+// from its registry.
+type Manifest struct {
+	Body        []byte
+	ContentType string
+	Digest      string
+}
+
+// IsList reports whether m is a manifest list / image index rather than a
+// single-platform manifest.
+func (m *Manifest) IsList() bool {
+	return strings.Contains(m.ContentType, "manifest.list") || strings.Contains(m.ContentType, "image.index")
+}
+
+// GetManifest fetches image's manifest from its registry, using authConfig
+// (which may be nil for anonymous pulls) as the registry credentials.
+func GetManifest(image string, authConfig *pb.AuthConfig) (*Manifest, error) {
+	resp, err := manifestRequest(http.MethodGet, image, authConfig)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading manifest body: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s from %s: %s", resp.Status, resp.Request.URL, string(body))
+	}
+
+	return &Manifest{
+		Body:        body,
+		ContentType: resp.Header.Get("Content-Type"),
+		Digest:      resp.Header.Get("Docker-Content-Digest"),
+	}, nil
+}
+
+// HeadManifestDigest issues a HEAD against image's manifest and returns the
+// Docker-Content-Digest the registry reports, without downloading the body.
+func HeadManifestDigest(image string, authConfig *pb.AuthConfig) (string, error) {
+	resp, err := manifestRequest(http.MethodHead, image, authConfig)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status %s from %s", resp.Status, resp.Request.URL)
+	}
+	digest := resp.Header.Get("Docker-Content-Digest")
+	if digest == "" {
+		return "", fmt.Errorf("registry did not return a Docker-Content-Digest header")
+	}
+	return digest, nil
+}
+
+func manifestRequest(method, image string, authConfig *pb.AuthConfig) (*http.Response, error) {
+	host, name, reference := SplitImageRef(image)
+	manifestURL := fmt.Sprintf("https://%s/v2/%s/manifests/%s", host, name, reference)
+
+	req, err := http.NewRequest(method, manifestURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", manifestAccept)
+
+	return doAuthenticated(req, fmt.Sprintf("repository:%s:pull", name), authConfig)
+}
+
+// doAuthenticated performs req, transparently handling the registry v2
+// Bearer challenge: on a 401 with a "WWW-Authenticate: Bearer ..." header it
+// fetches a token from the advertised realm and retries the request once.
+// scope is used as a fallback when the challenge itself doesn't specify one.
+func doAuthenticated(req *http.Request, scope string, authConfig *pb.AuthConfig) (*http.Response, error) {
+	setStaticAuth(req, authConfig)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusUnauthorized {
+		return resp, nil
+	}
+
+	challenge := resp.Header.Get("WWW-Authenticate")
+	resp.Body.Close()
+
+	realm, service, challengeScope, ok := parseBearerChallenge(challenge)
+	if !ok {
+		return nil, fmt.Errorf("registry returned 401 without a usable Bearer challenge: %q", challenge)
+	}
+	if challengeScope != "" {
+		scope = challengeScope
+	}
+
+	token, err := fetchBearerToken(realm, service, scope, authConfig)
+	if err != nil {
+		return nil, fmt.Errorf("fetching registry bearer token: %v", err)
+	}
+
+	retry := req.Clone(req.Context())
+	retry.Header.Set("Authorization", "Bearer "+token)
+	return http.DefaultClient.Do(retry)
+}
+
+var bearerChallengeParamRe = regexp.MustCompile(`(\w+)="([^"]*)"`)
+
+// parseBearerChallenge parses a "Bearer realm=\"...\",service=\"...\",scope=\"...\""
+// WWW-Authenticate header value.
+func parseBearerChallenge(header string) (realm, service, scope string, ok bool) {
+	if !strings.HasPrefix(header, "Bearer ") {
+		return "", "", "", false
+	}
+	for _, m := range bearerChallengeParamRe.FindAllStringSubmatch(header, -1) {
+		switch m[1] {
+		case "realm":
+			realm = m[2]
+		case "service":
+			service = m[2]
+		case "scope":
+			scope = m[2]
+		}
+	}
+	return realm, service, scope, realm != ""
+}
+
+// fetchBearerToken exchanges the registry's auth-server realm for a Bearer
+// token, authenticating the token request itself with authConfig if set
+// (this is how registries issue scoped tokens for private repos, and plain
+// unauthenticated tokens for anonymous/public pulls).
+func fetchBearerToken(realm, service, scope string, authConfig *pb.AuthConfig) (string, error) {
+	tokenURL, err := url.Parse(realm)
+	if err != nil {
+		return "", fmt.Errorf("invalid token realm %q: %v", realm, err)
+	}
+	q := tokenURL.Query()
+	if service != "" {
+		q.Set("service", service)
+	}
+	if scope != "" {
+		q.Set("scope", scope)
+	}
+	if authConfig != nil && authConfig.Username != "" {
+		q.Set("account", authConfig.Username)
+	}
+	tokenURL.RawQuery = q.Encode()
+
+	req, err := http.NewRequest(http.MethodGet, tokenURL.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	setStaticAuth(req, authConfig)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("reading token response: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status %s from token endpoint %s: %s", resp.Status, tokenURL, string(body))
+	}
+
+	var parsed struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.Unmarshal(body, &parsed); err != nil {
+		return "", fmt.Errorf("parsing token response: %v", err)
+	}
+	if parsed.Token != "" {
+		return parsed.Token, nil
+	}
+	if parsed.AccessToken != "" {
+		return parsed.AccessToken, nil
+	}
+	return "", fmt.Errorf("token endpoint %s returned neither token nor access_token", tokenURL)
+}
+
+// setStaticAuth attaches whatever credentials authConfig carries directly
+// (as opposed to the token fetched via the Bearer challenge): an existing
+// RegistryToken is sent as a bearer token outright, otherwise Username is
+// sent as HTTP Basic auth. Both the initial request and the token-endpoint
+// request use this, since either may already satisfy the registry without
+// a challenge, and the token endpoint itself uses Basic auth to identify the
+// caller.
+func setStaticAuth(req *http.Request, authConfig *pb.AuthConfig) {
+	if authConfig == nil {
+		return
+	}
+	if authConfig.RegistryToken != "" {
+		req.Header.Set("Authorization", "Bearer "+authConfig.RegistryToken)
+		return
+	}
+	if authConfig.Username != "" {
+		req.SetBasicAuth(authConfig.Username, authConfig.Password)
+	}
+}
+
+// SplitImageRef splits image into its registry host, repository name and
+// tag/digest reference, defaulting the host to Docker Hub's registry
+// endpoint the way docker/podman do. A bare, single-segment name on that
+// default host is a Docker Hub "official repository" and gets the implicit
+// "library/" namespace docker itself adds, e.g. "alpine" -> "library/alpine".
+func SplitImageRef(image string) (host, name, reference string) {
+	host = "registry-1.docker.io"
+	name = image
+	reference = "latest"
+
+	if idx := strings.Index(image, "/"); idx != -1 {
+		first := image[:idx]
+		if strings.ContainsAny(first, ".:") || first == "localhost" {
+			host = first
+			name = image[idx+1:]
+		}
+	}
+	if host == "registry-1.docker.io" && !strings.Contains(name, "/") {
+		name = "library/" + name
+	}
+
+	if idx := strings.LastIndex(name, "@"); idx != -1 {
+		reference = name[idx+1:]
+		name = name[:idx]
+		return host, name, reference
+	}
+	if idx := strings.LastIndex(name, ":"); idx != -1 {
+		reference = name[idx+1:]
+		name = name[:idx]
+	}
+	return host, name, reference
+}
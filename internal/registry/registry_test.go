@@ -0,0 +1,128 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	pb "k8s.io/kubernetes/pkg/kubelet/apis/cri/runtime/v1alpha2"
+)
+
+func TestSplitImageRef(t *testing.T) {
+	for _, tc := range []struct {
+		image         string
+		wantHost      string
+		wantName      string
+		wantReference string
+	}{
+		{"alpine", "registry-1.docker.io", "library/alpine", "latest"},
+		{"alpine:3.18", "registry-1.docker.io", "library/alpine", "3.18"},
+		{"my/img:v1", "registry-1.docker.io", "my/img", "v1"},
+		{"registry.io/team/app:v1", "registry.io", "team/app", "v1"},
+		{"registry.io/app@sha256:deadbeef", "registry.io", "app", "sha256:deadbeef"},
+		{"localhost:5000/app:v1", "localhost:5000", "app", "v1"},
+	} {
+		host, name, reference := SplitImageRef(tc.image)
+		if host != tc.wantHost || name != tc.wantName || reference != tc.wantReference {
+			t.Errorf("SplitImageRef(%q) = (%q, %q, %q), want (%q, %q, %q)",
+				tc.image, host, name, reference, tc.wantHost, tc.wantName, tc.wantReference)
+		}
+	}
+}
+
+func TestParseBearerChallenge(t *testing.T) {
+	header := `Bearer realm="https://auth.docker.io/token",service="registry.docker.io",scope="repository:library/alpine:pull"`
+	realm, service, scope, ok := parseBearerChallenge(header)
+	if !ok {
+		t.Fatal("expected a usable challenge")
+	}
+	if realm != "https://auth.docker.io/token" || service != "registry.docker.io" || scope != "repository:library/alpine:pull" {
+		t.Errorf("got realm=%q service=%q scope=%q", realm, service, scope)
+	}
+
+	if _, _, _, ok := parseBearerChallenge(`Basic realm="registry"`); ok {
+		t.Error("Basic challenges should not be treated as usable Bearer challenges")
+	}
+}
+
+func TestFetchBearerToken(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("service") != "registry.docker.io" || q.Get("scope") != "repository:library/alpine:pull" {
+			t.Errorf("unexpected token request query: %v", q)
+		}
+		json.NewEncoder(w).Encode(map[string]string{"token": "test-token"})
+	}))
+	defer server.Close()
+
+	token, err := fetchBearerToken(server.URL, "registry.docker.io", "repository:library/alpine:pull", nil)
+	if err != nil {
+		t.Fatalf("fetchBearerToken() error = %v", err)
+	}
+	if token != "test-token" {
+		t.Errorf("token = %q, want test-token", token)
+	}
+}
+
+func TestFetchBearerTokenAccessTokenFallback(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{"access_token": "fallback-token"})
+	}))
+	defer server.Close()
+
+	token, err := fetchBearerToken(server.URL, "", "", nil)
+	if err != nil {
+		t.Fatalf("fetchBearerToken() error = %v", err)
+	}
+	if token != "fallback-token" {
+		t.Errorf("token = %q, want fallback-token", token)
+	}
+}
+
+func TestSetStaticAuth(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodGet, "https://example.com", nil)
+	setStaticAuth(req, &pb.AuthConfig{RegistryToken: "rt"})
+	if got := req.Header.Get("Authorization"); got != "Bearer rt" {
+		t.Errorf("Authorization = %q, want Bearer rt", got)
+	}
+
+	req, _ = http.NewRequest(http.MethodGet, "https://example.com", nil)
+	setStaticAuth(req, &pb.AuthConfig{Username: "u", Password: "p"})
+	user, pass, ok := req.BasicAuth()
+	if !ok || user != "u" || pass != "p" {
+		t.Errorf("BasicAuth() = (%q, %q, %v), want (u, p, true)", user, pass, ok)
+	}
+}
+
+func TestManifestIsList(t *testing.T) {
+	for _, tc := range []struct {
+		contentType string
+		want        bool
+	}{
+		{"application/vnd.docker.distribution.manifest.list.v2+json", true},
+		{"application/vnd.oci.image.index.v1+json", true},
+		{"application/vnd.docker.distribution.manifest.v2+json", false},
+	} {
+		m := &Manifest{ContentType: tc.contentType}
+		if got := m.IsList(); got != tc.want {
+			t.Errorf("IsList() for %q = %v, want %v", tc.contentType, got, tc.want)
+		}
+	}
+}
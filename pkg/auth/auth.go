@@ -0,0 +1,253 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package auth resolves registry credentials the way docker/podman do, so
+// `crictl pull` and friends can read the same auth files and credential
+// helpers users already have configured for those tools.
+package auth
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	pb "k8s.io/kubernetes/pkg/kubelet/apis/cri/runtime/v1alpha2"
+)
+
+// authFileEntry is a single value of the auth file's top-level "auths" map.
+type authFileEntry struct {
+	Auth          string `json:"auth"`
+	IdentityToken string `json:"identitytoken"`
+	RegistryToken string `json:"registrytoken"`
+}
+
+type authFile struct {
+	Auths       map[string]authFileEntry `json:"auths"`
+	CredHelpers map[string]string        `json:"credHelpers"`
+}
+
+// ResolveAuth resolves credentials for image the same way `docker login`
+// backed tools do: by matching its registry host against the "auths" (and,
+// failing that, "credHelpers") entries of the first auth file found. authfile
+// takes precedence over $REGISTRY_AUTH_FILE, $DOCKER_CONFIG/config.json,
+// ~/.docker/config.json and $XDG_RUNTIME_DIR/containers/auth.json, in that
+// order. It returns (nil, nil) when no matching entry exists anywhere.
+func ResolveAuth(image, authfile string) (*pb.AuthConfig, error) {
+	path := locateAuthFile(authfile)
+	if path == "" {
+		return nil, nil
+	}
+
+	f, err := loadAuthFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading auth file %q: %v", path, err)
+	}
+
+	host, repo := splitRepository(image)
+	if _, entry, ok := matchAuthEntry(f.Auths, repo); ok {
+		return entryToAuthConfig(entry)
+	}
+
+	if helper, ok := matchCredHelper(f.CredHelpers, host); ok {
+		return runCredHelper(helper, host)
+	}
+
+	return nil, nil
+}
+
+// ResolveAuthWithHelper bypasses auth-file lookup and resolves credentials
+// for image directly from the named docker-credential-<name> helper, for
+// callers that were given an explicit --creds-helper.
+func ResolveAuthWithHelper(image, helper string) (*pb.AuthConfig, error) {
+	host, _ := splitRepository(image)
+	return runCredHelper(helper, host)
+}
+
+// locateAuthFile returns the first configured auth file path, in priority
+// order. preferred and $REGISTRY_AUTH_FILE are returned verbatim, without
+// checking that they exist, so an explicit setting produces a clear error
+// rather than silent fallback; the remaining locations are used only when
+// the file exists.
+func locateAuthFile(preferred string) string {
+	if preferred != "" {
+		return preferred
+	}
+	if env := os.Getenv("REGISTRY_AUTH_FILE"); env != "" {
+		return env
+	}
+	if dir := os.Getenv("DOCKER_CONFIG"); dir != "" {
+		if p := dir + "/config.json"; fileExists(p) {
+			return p
+		}
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		if p := filepath.Join(home, ".docker", "config.json"); fileExists(p) {
+			return p
+		}
+	}
+	if runtimeDir := os.Getenv("XDG_RUNTIME_DIR"); runtimeDir != "" {
+		if p := filepath.Join(runtimeDir, "containers", "auth.json"); fileExists(p) {
+			return p
+		}
+	}
+	return ""
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func loadAuthFile(path string) (*authFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	f := &authFile{}
+	if err := json.Unmarshal(data, f); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// splitRepository splits an image reference into its registry host and the
+// full "host/repository" path (with tag/digest stripped), defaulting the
+// host to "docker.io" the way docker/podman's auth resolution does.
+func splitRepository(image string) (host, repo string) {
+	name := image
+	if idx := strings.IndexAny(name, "@"); idx != -1 {
+		name = name[:idx]
+	}
+
+	host = "docker.io"
+	path := name
+	if idx := strings.Index(name, "/"); idx != -1 {
+		first := name[:idx]
+		if strings.ContainsAny(first, ".:") || first == "localhost" {
+			host = first
+			path = name[idx+1:]
+		}
+	}
+	// A ':' in path is a tag, not a port; ports only ever appear in host,
+	// which was already split off above.
+	if idx := strings.LastIndex(path, ":"); idx != -1 {
+		path = path[:idx]
+	}
+
+	return host, host + "/" + path
+}
+
+// matchAuthEntry finds the longest registry-prefix key in auths that
+// matches repo (a "host/repository" string, see splitRepository), so both
+// host-only entries (the common case) and entries scoped to a specific
+// namespace or repository (as podman's auth.json supports) are honored.
+// docker.io is additionally tried under Docker Hub's canonical aliases,
+// the way docker's own config.json resolution does.
+func matchAuthEntry(auths map[string]authFileEntry, repo string) (string, authFileEntry, bool) {
+	candidates := prefixCandidates(repo)
+	if rest, ok := strings.CutPrefix(repo, "docker.io/"); ok {
+		for _, alias := range []string{"index.docker.io/v1/", "index.docker.io", "https://index.docker.io/v1/"} {
+			candidates = append(candidates, alias)
+			candidates = append(candidates, prefixCandidates(strings.TrimSuffix(alias, "/")+"/"+rest)...)
+		}
+	}
+
+	var bestKey string
+	var best authFileEntry
+	found := false
+	for _, candidate := range candidates {
+		if entry, ok := auths[candidate]; ok && len(candidate) > len(bestKey) {
+			bestKey, best, found = candidate, entry, true
+		}
+	}
+	return bestKey, best, found
+}
+
+// prefixCandidates returns every "/"-boundary-aligned prefix of ref, longest
+// first, e.g. "registry.io/team/app" -> ["registry.io/team/app",
+// "registry.io/team", "registry.io"].
+func prefixCandidates(ref string) []string {
+	segments := strings.Split(ref, "/")
+	candidates := make([]string, 0, len(segments))
+	for i := len(segments); i > 0; i-- {
+		candidates = append(candidates, strings.Join(segments[:i], "/"))
+	}
+	return candidates
+}
+
+func matchCredHelper(helpers map[string]string, host string) (string, bool) {
+	if helper, ok := helpers[host]; ok {
+		return helper, true
+	}
+	if host == "docker.io" {
+		if helper, ok := helpers["index.docker.io/v1/"]; ok {
+			return helper, true
+		}
+	}
+	return "", false
+}
+
+func entryToAuthConfig(entry authFileEntry) (*pb.AuthConfig, error) {
+	auth := &pb.AuthConfig{
+		IdentityToken: entry.IdentityToken,
+		RegistryToken: entry.RegistryToken,
+	}
+	if entry.Auth != "" {
+		decoded, err := base64.StdEncoding.DecodeString(entry.Auth)
+		if err != nil {
+			return nil, fmt.Errorf("decoding auth entry: %v", err)
+		}
+		user, pass, ok := strings.Cut(string(decoded), ":")
+		if !ok {
+			return nil, fmt.Errorf("malformed auth entry, expected user:password")
+		}
+		auth.Username = user
+		auth.Password = pass
+	}
+	return auth, nil
+}
+
+// credHelperOutput is the JSON a `docker-credential-<name> get` helper
+// writes to stdout, per the credential-helper protocol.
+type credHelperOutput struct {
+	Username string `json:"Username"`
+	Secret   string `json:"Secret"`
+}
+
+// runCredHelper shells out to docker-credential-<name>, writing serverURL to
+// its stdin and decoding the {"Username","Secret"} JSON it prints back.
+func runCredHelper(name, serverURL string) (*pb.AuthConfig, error) {
+	cmd := exec.Command("docker-credential-"+name, "get")
+	cmd.Stdin = strings.NewReader(serverURL)
+	var stdout bytes.Buffer
+	cmd.Stdout = &stdout
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("running credential helper %q: %v", name, err)
+	}
+	out := credHelperOutput{}
+	if err := json.Unmarshal(stdout.Bytes(), &out); err != nil {
+		return nil, fmt.Errorf("parsing credential helper %q output: %v", name, err)
+	}
+	return &pb.AuthConfig{
+		Username: out.Username,
+		Password: out.Secret,
+	}, nil
+}
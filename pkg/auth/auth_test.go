@@ -0,0 +1,94 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package auth
+
+import "testing"
+
+func TestSplitRepository(t *testing.T) {
+	for _, tc := range []struct {
+		image    string
+		wantHost string
+		wantRepo string
+	}{
+		{"alpine", "docker.io", "docker.io/alpine"},
+		{"alpine:3.18", "docker.io", "docker.io/alpine"},
+		{"my/img", "docker.io", "docker.io/my/img"},
+		{"registry.io/team/app:v1", "registry.io", "registry.io/team/app"},
+		{"localhost:5000/app", "localhost:5000", "localhost:5000/app"},
+	} {
+		host, repo := splitRepository(tc.image)
+		if host != tc.wantHost || repo != tc.wantRepo {
+			t.Errorf("splitRepository(%q) = (%q, %q), want (%q, %q)", tc.image, host, repo, tc.wantHost, tc.wantRepo)
+		}
+	}
+}
+
+func TestMatchAuthEntryHostOnly(t *testing.T) {
+	auths := map[string]authFileEntry{
+		"registry.io": {Auth: "aG9zdDpwYXNz"},
+	}
+	_, entry, ok := matchAuthEntry(auths, "registry.io/team/app")
+	if !ok {
+		t.Fatal("expected host-only entry to match a namespaced repo")
+	}
+	if entry.Auth != "aG9zdDpwYXNz" {
+		t.Errorf("matched wrong entry: %+v", entry)
+	}
+}
+
+func TestMatchAuthEntryLongestPrefixWins(t *testing.T) {
+	auths := map[string]authFileEntry{
+		"registry.io":      {Auth: "aG9zdDpwYXNz"},
+		"registry.io/team": {Auth: "dGVhbTpwYXNz"},
+	}
+	key, entry, ok := matchAuthEntry(auths, "registry.io/team/app")
+	if !ok || key != "registry.io/team" {
+		t.Fatalf("expected the more specific registry.io/team entry to win, got key=%q ok=%v", key, ok)
+	}
+	if entry.Auth != "dGVhbTpwYXNz" {
+		t.Errorf("matched wrong entry: %+v", entry)
+	}
+}
+
+func TestMatchAuthEntryDockerHubAlias(t *testing.T) {
+	auths := map[string]authFileEntry{
+		"https://index.docker.io/v1/": {Auth: "aHViOnBhc3M="},
+	}
+	if _, _, ok := matchAuthEntry(auths, "docker.io/library/alpine"); !ok {
+		t.Fatal("expected docker.io reference to match the index.docker.io/v1 alias")
+	}
+}
+
+func TestMatchAuthEntryNoMatch(t *testing.T) {
+	auths := map[string]authFileEntry{
+		"other.io": {Auth: "eA=="},
+	}
+	if _, _, ok := matchAuthEntry(auths, "registry.io/app"); ok {
+		t.Fatal("expected no match for an unrelated registry")
+	}
+}
+
+func TestEntryToAuthConfig(t *testing.T) {
+	entry := authFileEntry{Auth: "dXNlcjpwYXNz"} // user:pass
+	cfg, err := entryToAuthConfig(entry)
+	if err != nil {
+		t.Fatalf("entryToAuthConfig() error = %v", err)
+	}
+	if cfg.Username != "user" || cfg.Password != "pass" {
+		t.Errorf("got username=%q password=%q, want user/pass", cfg.Username, cfg.Password)
+	}
+}
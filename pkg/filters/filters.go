@@ -0,0 +1,194 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package filters implements the libimage-style predicate language accepted
+// by the `--filter`/`-f` flag of `crictl images` and `crictl image prune`.
+package filters
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	pb "k8s.io/kubernetes/pkg/kubelet/apis/cri/runtime/v1alpha2"
+)
+
+// ImageFilter holds the parsed predicates for a single `--filter` invocation.
+// A zero-valued field means the corresponding predicate was not requested.
+type ImageFilter struct {
+	// Reference is a shell-style glob matched against the repository portion
+	// (i.e. without tag or digest) of each RepoTag/RepoDigest.
+	Reference string
+
+	// Dangling, when non-nil, restricts to images without (true) or with
+	// (false) at least one usable RepoTag (see IsDangling).
+	Dangling *bool
+
+	// ID matches a (possibly truncated, "sha256:" prefix optional) image ID.
+	ID string
+
+	// Labels are ANDed together; an empty value means "key must be present",
+	// any value matches.
+	Labels map[string]string
+
+	// BeforeImage/SinceImage are the raw, still-unresolved references passed
+	// to before=/since=. Resolving them (via ImageStatus, with a list-order
+	// fallback when no creation time is available) needs more context than a
+	// single image and its info, so callers evaluate these themselves instead
+	// of going through Match; see resolveCreatedAt and filterImages in
+	// cmd/crictl/image.go.
+	BeforeImage string
+	SinceImage  string
+
+	// Until is the raw duration string passed to until=; consumers
+	// (currently only `image prune`) resolve it against CreatedAt.
+	Until string
+}
+
+// Parse turns the repeated `-f key=value` flag values into an ImageFilter.
+// Unknown keys are rejected so typos don't silently match everything.
+func Parse(raw []string) (*ImageFilter, error) {
+	f := &ImageFilter{}
+	for _, kv := range raw {
+		key, value, _ := strings.Cut(kv, "=")
+		switch key {
+		case "reference":
+			f.Reference = value
+		case "dangling":
+			b, err := parseBool(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid dangling filter %q: %v", value, err)
+			}
+			f.Dangling = &b
+		case "before":
+			f.BeforeImage = value
+		case "since":
+			f.SinceImage = value
+		case "id":
+			f.ID = value
+		case "until":
+			f.Until = value
+		case "label":
+			if f.Labels == nil {
+				f.Labels = map[string]string{}
+			}
+			lk, lv, _ := strings.Cut(value, "=")
+			f.Labels[lk] = lv
+		default:
+			return nil, fmt.Errorf("unknown filter key %q", key)
+		}
+	}
+	return f, nil
+}
+
+func parseBool(value string) (bool, error) {
+	switch value {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	default:
+		return false, fmt.Errorf("must be true or false")
+	}
+}
+
+// Match reports whether image satisfies every predicate set on f. info is the
+// already-parsed label set for image (see ParseLabels), and may be nil when
+// no label filter is in effect and the caller wants to avoid the verbose
+// ImageStatus round trip.
+func (f *ImageFilter) Match(image *pb.Image, info map[string]string) bool {
+	if f.Reference != "" && !matchReference(f.Reference, image) {
+		return false
+	}
+	if f.Dangling != nil && *f.Dangling != isDangling(image) {
+		return false
+	}
+	if f.ID != "" && !matchID(f.ID, image.Id) {
+		return false
+	}
+	if len(f.Labels) > 0 {
+		for k, v := range f.Labels {
+			got, ok := info[k]
+			if !ok || (v != "" && got != v) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// matchReference matches pattern against the repository portion (everything
+// before the last ':' tag separator, or '@' digest separator) of every
+// RepoTag/RepoDigest, anchored on repository boundaries so that "foo" matches
+// "my/foo" but not "myfoo" — including when pattern itself is a multi-segment
+// glob, e.g. "my/img*" matching "registry.io/my/img123".
+func matchReference(pattern string, image *pb.Image) bool {
+	for _, ref := range append(append([]string{}, image.RepoTags...), image.RepoDigests...) {
+		if matchesRepoSuffix(pattern, repository(ref)) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesRepoSuffix glob-matches pattern against every "/"-boundary-aligned
+// suffix of repo (e.g. for "registry.io/my/img" that's "registry.io/my/img",
+// "my/img" and "img"), so a pattern anchors on a path component boundary
+// regardless of how many segments it itself spans.
+func matchesRepoSuffix(pattern, repo string) bool {
+	segments := strings.Split(repo, "/")
+	for i := range segments {
+		suffix := strings.Join(segments[i:], "/")
+		if ok, _ := path.Match(pattern, suffix); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func repository(ref string) string {
+	if idx := strings.LastIndex(ref, "@"); idx != -1 {
+		return ref[:idx]
+	}
+	if idx := strings.LastIndex(ref, ":"); idx != -1 && !strings.Contains(ref[idx:], "/") {
+		return ref[:idx]
+	}
+	return ref
+}
+
+// IsDangling reports whether image has no usable RepoTags, i.e. it is only
+// reachable by ID or digest.
+func IsDangling(image *pb.Image) bool {
+	return isDangling(image)
+}
+
+func isDangling(image *pb.Image) bool {
+	if len(image.RepoTags) == 0 {
+		return true
+	}
+	for _, tag := range image.RepoTags {
+		if tag != "<none>:<none>" {
+			return false
+		}
+	}
+	return true
+}
+
+func matchID(pattern, id string) bool {
+	pattern = strings.TrimPrefix(pattern, "sha256:")
+	trimmed := strings.TrimPrefix(id, "sha256:")
+	return strings.HasPrefix(trimmed, pattern)
+}
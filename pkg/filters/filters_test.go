@@ -0,0 +1,82 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package filters
+
+import (
+	"testing"
+
+	pb "k8s.io/kubernetes/pkg/kubelet/apis/cri/runtime/v1alpha2"
+)
+
+func TestMatchReference(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		pattern string
+		repoTag string
+		want    bool
+	}{
+		{"exact", "foo", "foo:latest", true},
+		{"boundary match", "foo", "my/foo:latest", true},
+		{"no false boundary match", "foo", "myfoo:latest", false},
+		{"multi-segment glob", "my/img*", "registry.io/my/img123:latest", true},
+		{"multi-segment glob no match", "my/img*", "registry.io/other/img123:latest", false},
+		{"single-segment glob", "img*", "registry.io/my/img123:latest", true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			f := &ImageFilter{Reference: tc.pattern}
+			image := &pb.Image{RepoTags: []string{tc.repoTag}}
+			if got := f.Match(image, nil); got != tc.want {
+				t.Errorf("Match() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	f, err := Parse([]string{"dangling=true", "label=env=prod", "label=tier"})
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if f.Dangling == nil || !*f.Dangling {
+		t.Errorf("Dangling = %v, want true", f.Dangling)
+	}
+	if f.Labels["env"] != "prod" || f.Labels["tier"] != "" {
+		t.Errorf("Labels = %v, want env=prod and tier present", f.Labels)
+	}
+
+	if _, err := Parse([]string{"bogus=1"}); err == nil {
+		t.Error("Parse() with unknown key should have errored")
+	}
+}
+
+func TestIsDangling(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		tags []string
+		want bool
+	}{
+		{"no tags", nil, true},
+		{"none tag", []string{"<none>:<none>"}, true},
+		{"real tag", []string{"foo:latest"}, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := IsDangling(&pb.Image{RepoTags: tc.tags}); got != tc.want {
+				t.Errorf("IsDangling() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
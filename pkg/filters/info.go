@@ -0,0 +1,77 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package filters
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// containerdImageInfo and crioImageInfo model just enough of the two
+// runtimes' verbose ImageStatus "info" payload to recover labels and
+// creation time; CRI gives us no stable, runtime-agnostic schema for this.
+type containerdImageInfo struct {
+	ImageSpec struct {
+		Created time.Time `json:"created"`
+		Config  struct {
+			Labels map[string]string `json:"Labels"`
+		} `json:"config"`
+	} `json:"imageSpec"`
+}
+
+type crioImageInfo struct {
+	Labels  map[string]string `json:"labels"`
+	Created *time.Time        `json:"created"`
+}
+
+// ParseLabels recovers the OCI image labels from the "info" entry of a
+// verbose ImageStatusResponse, tolerating the different shapes containerd
+// and CRI-O embed them in. Returns nil if no labels could be recovered.
+func ParseLabels(info map[string]string) map[string]string {
+	raw, ok := info["info"]
+	if !ok {
+		return nil
+	}
+	var containerd containerdImageInfo
+	if err := json.Unmarshal([]byte(raw), &containerd); err == nil && len(containerd.ImageSpec.Config.Labels) > 0 {
+		return containerd.ImageSpec.Config.Labels
+	}
+	var crio crioImageInfo
+	if err := json.Unmarshal([]byte(raw), &crio); err == nil && len(crio.Labels) > 0 {
+		return crio.Labels
+	}
+	return nil
+}
+
+// CreatedAt recovers the image creation time from the same "info" entry
+// used by ParseLabels. ok is false when no runtime-recognized shape yielded
+// a timestamp, in which case callers should fall back to list order.
+func CreatedAt(info map[string]string) (t time.Time, ok bool) {
+	raw, present := info["info"]
+	if !present {
+		return time.Time{}, false
+	}
+	var containerd containerdImageInfo
+	if err := json.Unmarshal([]byte(raw), &containerd); err == nil && !containerd.ImageSpec.Created.IsZero() {
+		return containerd.ImageSpec.Created, true
+	}
+	var crio crioImageInfo
+	if err := json.Unmarshal([]byte(raw), &crio); err == nil && crio.Created != nil {
+		return *crio.Created, true
+	}
+	return time.Time{}, false
+}
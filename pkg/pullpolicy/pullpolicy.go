@@ -0,0 +1,104 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package pullpolicy implements the libimage pull-policy vocabulary
+// (always/missing/never/newer) behind `crictl pull`'s --pull-policy flag.
+// ShouldPull takes a pb.ImageServiceClient rather than anything pull-specific
+// so a future `crictl run`/`crictl create` can reuse it the same way; neither
+// command exists in this tree yet, so that reuse hasn't happened here.
+package pullpolicy
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/context"
+	pb "k8s.io/kubernetes/pkg/kubelet/apis/cri/runtime/v1alpha2"
+
+	"github.com/kubernetes-sigs/cri-tools/internal/registry"
+)
+
+// headManifestDigest is registry.HeadManifestDigest, indirected so tests can
+// stub it out instead of hitting a real registry.
+var headManifestDigest = registry.HeadManifestDigest
+
+// Policy selects when an image is actually pulled from the registry versus
+// satisfied from local storage.
+type Policy string
+
+const (
+	// Always pulls unconditionally, the crictl pull default.
+	Always Policy = "always"
+	// Missing pulls only when no matching image exists locally.
+	Missing Policy = "missing"
+	// Never never pulls; it errors when the image isn't already present.
+	Never Policy = "never"
+	// Newer pulls only when the registry's digest differs from every
+	// locally known RepoDigest for the image.
+	Newer Policy = "newer"
+)
+
+// Parse validates and normalizes a --pull-policy flag value.
+func Parse(s string) (Policy, error) {
+	switch p := Policy(strings.ToLower(s)); p {
+	case Always, Missing, Never, Newer:
+		return p, nil
+	default:
+		return "", fmt.Errorf("invalid pull policy %q: must be one of always, missing, never, newer", s)
+	}
+}
+
+// ShouldPull reports whether the PullImage RPC should be issued for image
+// under policy, consulting the local image store (and, for Newer, the
+// registry) as needed. local is the already-resolved local image, if any, so
+// callers that also need it to report "already present" don't have to ask
+// ImageStatus a second time.
+func ShouldPull(client pb.ImageServiceClient, image string, auth *pb.AuthConfig, policy Policy) (pull bool, local *pb.Image, err error) {
+	if policy == Always {
+		return true, nil, nil
+	}
+
+	status, err := client.ImageStatus(context.Background(), &pb.ImageStatusRequest{Image: &pb.ImageSpec{Image: image}})
+	if err != nil {
+		return false, nil, fmt.Errorf("checking local image status: %v", err)
+	}
+
+	switch policy {
+	case Missing:
+		return status.Image == nil, status.Image, nil
+	case Never:
+		if status.Image == nil {
+			return false, nil, fmt.Errorf("image %q not present locally and pull policy is never", image)
+		}
+		return false, status.Image, nil
+	case Newer:
+		if status.Image == nil {
+			return true, nil, nil
+		}
+		remote, err := headManifestDigest(image, auth)
+		if err != nil {
+			return false, nil, fmt.Errorf("checking remote digest: %v", err)
+		}
+		for _, d := range status.Image.RepoDigests {
+			if strings.HasSuffix(d, "@"+remote) {
+				return false, status.Image, nil
+			}
+		}
+		return true, nil, nil
+	default:
+		return true, nil, nil
+	}
+}
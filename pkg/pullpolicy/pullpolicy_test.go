@@ -0,0 +1,153 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pullpolicy
+
+import (
+	"fmt"
+	"testing"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	pb "k8s.io/kubernetes/pkg/kubelet/apis/cri/runtime/v1alpha2"
+)
+
+// fakeImageServiceClient answers ImageStatus from a canned response/error;
+// every other pb.ImageServiceClient method is left unimplemented since
+// ShouldPull never calls them.
+type fakeImageServiceClient struct {
+	pb.ImageServiceClient
+	status *pb.ImageStatusResponse
+	err    error
+}
+
+func (f *fakeImageServiceClient) ImageStatus(ctx context.Context, req *pb.ImageStatusRequest, opts ...grpc.CallOption) (*pb.ImageStatusResponse, error) {
+	return f.status, f.err
+}
+
+func TestParse(t *testing.T) {
+	for _, tc := range []struct {
+		in      string
+		want    Policy
+		wantErr bool
+	}{
+		{"always", Always, false},
+		{"MISSING", Missing, false},
+		{"never", Never, false},
+		{"newer", Newer, false},
+		{"bogus", "", true},
+	} {
+		got, err := Parse(tc.in)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("Parse(%q) error = %v, wantErr %v", tc.in, err, tc.wantErr)
+		}
+		if got != tc.want {
+			t.Errorf("Parse(%q) = %q, want %q", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestShouldPullAlways(t *testing.T) {
+	client := &fakeImageServiceClient{err: nil}
+	pull, _, err := ShouldPull(client, "alpine", nil, Always)
+	if err != nil {
+		t.Fatalf("ShouldPull() error = %v", err)
+	}
+	if !pull {
+		t.Error("Always policy should always pull")
+	}
+}
+
+func TestShouldPullMissing(t *testing.T) {
+	client := &fakeImageServiceClient{status: &pb.ImageStatusResponse{}}
+	pull, _, err := ShouldPull(client, "alpine", nil, Missing)
+	if err != nil {
+		t.Fatalf("ShouldPull() error = %v", err)
+	}
+	if !pull {
+		t.Error("Missing policy should pull when no local image exists")
+	}
+
+	client = &fakeImageServiceClient{status: &pb.ImageStatusResponse{Image: &pb.Image{Id: "sha256:abc"}}}
+	pull, local, err := ShouldPull(client, "alpine", nil, Missing)
+	if err != nil {
+		t.Fatalf("ShouldPull() error = %v", err)
+	}
+	if pull {
+		t.Error("Missing policy should not pull when a local image already exists")
+	}
+	if local == nil || local.Id != "sha256:abc" {
+		t.Errorf("expected the local image to be returned, got %v", local)
+	}
+}
+
+func TestShouldPullNewer(t *testing.T) {
+	defer func(orig func(string, *pb.AuthConfig) (string, error)) { headManifestDigest = orig }(headManifestDigest)
+
+	client := &fakeImageServiceClient{status: &pb.ImageStatusResponse{}}
+	pull, _, err := ShouldPull(client, "alpine", nil, Newer)
+	if err != nil {
+		t.Fatalf("ShouldPull() error = %v", err)
+	}
+	if !pull {
+		t.Error("Newer policy should pull when no local image exists")
+	}
+
+	local := &pb.Image{Id: "sha256:abc", RepoDigests: []string{"alpine@sha256:current"}}
+	client = &fakeImageServiceClient{status: &pb.ImageStatusResponse{Image: local}}
+
+	headManifestDigest = func(image string, auth *pb.AuthConfig) (string, error) { return "sha256:current", nil }
+	pull, got, err := ShouldPull(client, "alpine", nil, Newer)
+	if err != nil {
+		t.Fatalf("ShouldPull() error = %v", err)
+	}
+	if pull {
+		t.Error("Newer policy should not pull when the remote digest matches a local RepoDigest")
+	}
+	if got != local {
+		t.Errorf("expected the local image to be returned, got %v", got)
+	}
+
+	headManifestDigest = func(image string, auth *pb.AuthConfig) (string, error) { return "sha256:newer", nil }
+	pull, _, err = ShouldPull(client, "alpine", nil, Newer)
+	if err != nil {
+		t.Fatalf("ShouldPull() error = %v", err)
+	}
+	if !pull {
+		t.Error("Newer policy should pull when the remote digest differs from every local RepoDigest")
+	}
+
+	headManifestDigest = func(image string, auth *pb.AuthConfig) (string, error) { return "", fmt.Errorf("boom") }
+	if _, _, err := ShouldPull(client, "alpine", nil, Newer); err == nil {
+		t.Error("Newer policy should surface a registry error instead of silently pulling or skipping")
+	}
+}
+
+func TestShouldPullNever(t *testing.T) {
+	client := &fakeImageServiceClient{status: &pb.ImageStatusResponse{}}
+	if _, _, err := ShouldPull(client, "alpine", nil, Never); err == nil {
+		t.Error("Never policy should error when the image is not present locally")
+	}
+
+	client = &fakeImageServiceClient{status: &pb.ImageStatusResponse{Image: &pb.Image{Id: "sha256:abc"}}}
+	pull, _, err := ShouldPull(client, "alpine", nil, Never)
+	if err != nil {
+		t.Fatalf("ShouldPull() error = %v", err)
+	}
+	if pull {
+		t.Error("Never policy should never pull")
+	}
+}